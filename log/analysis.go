@@ -0,0 +1,160 @@
+package log
+
+import (
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// maxMessageTemplates bounds how many distinct templates are tallied.
+const maxMessageTemplates = 1024
+
+// OtherTemplate tallies messages whose template first appeared after
+// maxMessageTemplates distinct templates were already being tallied.
+const OtherTemplate = "<other>"
+
+// NormalizeRule replaces every match of Pattern with Placeholder when a
+// message is reduced to its template.
+type NormalizeRule struct {
+	Pattern     *regexp.Regexp
+	Placeholder string
+}
+
+// MessageStat is the number of written messages that reduced to Template.
+type MessageStat struct {
+	Template string
+	Count    uint64
+}
+
+var defaultNormalizeRules = []NormalizeRule{
+	{regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`), "<uuid>"},
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>"},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}\b`), "<ip>"},
+	{regexp.MustCompile(`0x[0-9a-fA-F]+`), "<hex>"},
+	{regexp.MustCompile(`\d+(\.\d+)?`), "<num>"},
+}
+
+var analysisEnabled int32
+var analysisMutex sync.Mutex
+var analysisRules []NormalizeRule
+var templateCounts map[string]uint64
+
+// DefaultNormalizeRules returns the rules used when EnableMessageAnalysis is
+// given none. They replace UUIDs, timestamps, IPv4 addresses, hex and
+// decimal numbers with placeholders.
+func DefaultNormalizeRules() []NormalizeRule {
+	return append([]NormalizeRule(nil), defaultNormalizeRules...)
+}
+
+// EnableMessageAnalysis starts tallying written messages by template, to
+// help find the noisiest log lines. A message's template is the message with
+// each rule applied in order; nil rules means DefaultNormalizeRules. Any
+// existing tally is discarded.
+func EnableMessageAnalysis(rules []NormalizeRule) {
+	if rules == nil {
+		rules = DefaultNormalizeRules()
+	}
+
+	analysisMutex.Lock()
+	analysisRules = rules
+	templateCounts = make(map[string]uint64)
+	atomic.StoreInt32(&analysisEnabled, 1)
+	analysisMutex.Unlock()
+}
+
+// DisableMessageAnalysis stops tallying messages and discards the tally.
+func DisableMessageAnalysis() {
+	analysisMutex.Lock()
+	atomic.StoreInt32(&analysisEnabled, 0)
+	analysisRules = nil
+	templateCounts = nil
+	analysisMutex.Unlock()
+}
+
+// TopMessages returns up to n templates with the highest counts, most
+// frequent first.
+func TopMessages(n int) []MessageStat {
+	analysisMutex.Lock()
+	stats := make([]MessageStat, 0, len(templateCounts))
+	for t, c := range templateCounts {
+		stats = append(stats, MessageStat{t, c})
+	}
+	analysisMutex.Unlock()
+
+	sort.Sort(byCount(stats))
+
+	if n < 0 {
+		n = 0
+	}
+
+	if n < len(stats) {
+		stats = stats[:n]
+	}
+
+	return stats
+}
+
+func analyzeMessage(m string) {
+	if atomic.LoadInt32(&analysisEnabled) == 0 {
+		return
+	}
+
+	analysisMutex.Lock()
+	rules := analysisRules
+	analysisMutex.Unlock()
+
+	t := normalizeMessage(m, rules)
+
+	analysisMutex.Lock()
+	defer analysisMutex.Unlock()
+
+	// Analysis may have been disabled while normalizing.
+	if templateCounts == nil {
+		return
+	}
+
+	if _, ok := templateCounts[t]; !ok && len(templateCounts) >= maxMessageTemplates {
+		t = OtherTemplate
+	}
+
+	templateCounts[t]++
+}
+
+// Normalize returns the template of m: m with placeholders in place of the
+// parts that vary between otherwise identical messages, such as UUIDs and
+// timestamps. It applies the rules given to EnableMessageAnalysis, or
+// DefaultNormalizeRules while analysis is off.
+func Normalize(m string) string {
+	analysisMutex.Lock()
+	rules := analysisRules
+	analysisMutex.Unlock()
+
+	if rules == nil {
+		rules = defaultNormalizeRules
+	}
+
+	return normalizeMessage(m, rules)
+}
+
+func normalizeMessage(m string, rules []NormalizeRule) string {
+	for _, r := range rules {
+		m = r.Pattern.ReplaceAllString(m, r.Placeholder)
+	}
+
+	return m
+}
+
+// byCount orders MessageStats by descending count, then by template.
+type byCount []MessageStat
+
+func (s byCount) Len() int      { return len(s) }
+func (s byCount) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s byCount) Less(i, j int) bool {
+	if s[i].Count != s[j].Count {
+		return s[i].Count > s[j].Count
+	}
+
+	return s[i].Template < s[j].Template
+}
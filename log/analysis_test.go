@@ -0,0 +1,109 @@
+package log
+
+import (
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestTopMessagesCollapsesTemplates(t *testing.T) {
+	setupTestWriter(TRACE)
+	EnableMessageAnalysis(nil)
+	defer DisableMessageAnalysis()
+
+	Info("user 42 logged in")
+	Infof("user %d logged in", 7)
+	Infof("user %d logged in", 1001)
+	Warnf("request %s failed", "3f2504e0-4f89-11d3-9a0c-0305e82c3301")
+	Warnf("request %s failed", "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	Error("pointer 0x1f3a is invalid")
+
+	expected := []MessageStat{
+		{"user <num> logged in", 3},
+		{"request <uuid> failed", 2},
+		{"pointer <hex> is invalid", 1},
+	}
+
+	stats := TopMessages(10)
+	if len(stats) != len(expected) {
+		t.Fatalf("expected %d templates, got %+v", len(expected), stats)
+	}
+
+	for i := range expected {
+		if stats[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], stats[i])
+		}
+	}
+
+	if top := TopMessages(1); len(top) != 1 || top[0] != expected[0] {
+		t.Errorf("TopMessages(1) returned %+v", top)
+	}
+}
+
+func TestMessageAnalysisCustomRules(t *testing.T) {
+	setupTestWriter(TRACE)
+	EnableMessageAnalysis([]NormalizeRule{
+		{regexp.MustCompile(`user \w+`), "user <name>"},
+	})
+	defer DisableMessageAnalysis()
+
+	Info("user alice logged in")
+	Info("user bob logged in")
+	Info("retry 3")
+
+	stats := TopMessages(10)
+	if len(stats) != 2 || stats[0] != (MessageStat{"user <name> logged in", 2}) || stats[1] != (MessageStat{"retry 3", 1}) {
+		t.Errorf("unexpected templates: %+v", stats)
+	}
+}
+
+func TestMessageAnalysisBounded(t *testing.T) {
+	EnableMessageAnalysis([]NormalizeRule{})
+	defer DisableMessageAnalysis()
+
+	for i := 0; i < maxMessageTemplates+5; i++ {
+		analyzeMessage("message " + strconv.Itoa(i))
+	}
+
+	stats := TopMessages(maxMessageTemplates + 10)
+	if len(stats) != maxMessageTemplates+1 || stats[0] != (MessageStat{OtherTemplate, 5}) {
+		t.Errorf("expected %d templates led by %s, got %d led by %+v", maxMessageTemplates+1, OtherTemplate, len(stats), stats[0])
+	}
+}
+
+func TestMessageAnalysisDisabled(t *testing.T) {
+	setupTestWriter(TRACE)
+	DisableMessageAnalysis()
+
+	Info("not tallied")
+
+	if stats := TopMessages(10); len(stats) != 0 {
+		t.Errorf("expected no templates while disabled, got %+v", stats)
+	}
+}
+
+func TestNormalizeCollapsesUUIDsAndTimestamps(t *testing.T) {
+	DisableMessageAnalysis()
+
+	a := Normalize("2013-01-02T15:04:05Z request 3f2504e0-4f89-11d3-9a0c-0305e82c3301 from 10.0.0.1 took 12ms")
+	b := Normalize("2013-06-30 23:59:59.123+02:00 request 6ba7b810-9dad-11d1-80b4-00c04fd430c8 from 192.168.1.20 took 7ms")
+
+	if a != b {
+		t.Errorf("expected identical templates, got %q and %q", a, b)
+	}
+
+	if expected := "<time> request <uuid> from <ip> took <num>ms"; a != expected {
+		t.Errorf("expected %q, got %q", expected, a)
+	}
+}
+
+func TestNormalizeUsesAnalysisRules(t *testing.T) {
+	EnableMessageAnalysis([]NormalizeRule{
+		{regexp.MustCompile(`user \w+`), "user <name>"},
+	})
+	defer DisableMessageAnalysis()
+
+	if m := Normalize("user alice retried 3 times"); m != "user <name> retried 3 times" {
+		t.Errorf("expected custom rules to apply, got %q", m)
+	}
+}
@@ -0,0 +1,40 @@
+package log
+
+import (
+	"sync/atomic"
+)
+
+var levelCounts [PANIC + 1]uint64
+
+func countLevel(level LogLevel) {
+	if level >= TRACE && level <= PANIC {
+		atomic.AddUint64(&levelCounts[level], 1)
+	}
+}
+
+// LevelCounts returns the number of messages written at each level since
+// the process started or ResetLevelCounts was last called. Messages
+// filtered out by the log level are not counted.
+func LevelCounts() map[LogLevel]uint64 {
+	counts := make(map[LogLevel]uint64)
+
+	for l := TRACE; l <= PANIC; l++ {
+		counts[l] = atomic.LoadUint64(&levelCounts[l])
+	}
+
+	return counts
+}
+
+// ResetLevelCounts zeroes the per-level counts and returns their values
+// from just before the reset. Each count is swapped atomically, so a message
+// written concurrently lands either in the result or in the next period;
+// none are lost between reading and resetting.
+func ResetLevelCounts() map[LogLevel]uint64 {
+	counts := make(map[LogLevel]uint64)
+
+	for l := TRACE; l <= PANIC; l++ {
+		counts[l] = atomic.SwapUint64(&levelCounts[l], 0)
+	}
+
+	return counts
+}
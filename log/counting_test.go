@@ -0,0 +1,81 @@
+package log
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestLevelCounts(t *testing.T) {
+	ResetLevelCounts()
+
+	countLevel(INFO)
+	countLevel(INFO)
+	countLevel(WARN)
+	countLevel(PANIC)
+	countLevel(LogLevel(42))
+
+	expected := map[LogLevel]uint64{TRACE: 0, DEBUG: 0, INFO: 2, WARN: 1, ERROR: 0, FATAL: 0, PANIC: 1}
+	counts := LevelCounts()
+
+	if len(counts) != len(expected) {
+		t.Fatalf("expected %d levels, got %v", len(expected), counts)
+	}
+
+	for l, c := range expected {
+		if counts[l] != c {
+			t.Errorf("expected %d messages at level %d, got %d", c, l, counts[l])
+		}
+	}
+
+	reset := ResetLevelCounts()
+	for l, c := range expected {
+		if reset[l] != c {
+			t.Errorf("expected reset to return %d for level %d, got %d", c, l, reset[l])
+		}
+	}
+
+	for l, c := range LevelCounts() {
+		if c != 0 {
+			t.Errorf("expected level %d to be reset, got %d", l, c)
+		}
+	}
+}
+
+func TestResetLevelCountsLosesNothing(t *testing.T) {
+	ResetLevelCounts()
+
+	const goroutines, perGoroutine = 4, 10000
+	var wg sync.WaitGroup
+
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				countLevel(ERROR)
+			}
+		}()
+	}
+
+	done := make(chan bool)
+	var total uint64
+
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				total += ResetLevelCounts()[ERROR]
+			}
+		}
+	}()
+
+	wg.Wait()
+	done <- true
+	total += ResetLevelCounts()[ERROR]
+
+	if total != goroutines*perGoroutine {
+		t.Errorf("expected %d messages across resets, got %d", goroutines*perGoroutine, total)
+	}
+}
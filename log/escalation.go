@@ -0,0 +1,86 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxEscalationKeys bounds how many distinct WARN messages are tracked; the
+// least recently logged message is forgotten once the limit is reached.
+const maxEscalationKeys = 1024
+
+// now is used to time WARN messages for escalation; tests replace it.
+var now = time.Now
+
+var escalationEnabled int32
+var escalationMutex sync.Mutex
+var escalationThreshold int
+var escalationWindow time.Duration
+var warnTimes = newKeyCache(maxEscalationKeys)
+
+// SetWarnEscalation makes an identical WARN message that is written
+// threshold times within window also get logged at ERROR. The WARN messages
+// themselves are still written, and the count restarts after each
+// escalation. A threshold of 0 or less turns escalation off.
+func SetWarnEscalation(threshold int, window time.Duration) {
+	escalationMutex.Lock()
+	escalationThreshold = threshold
+	escalationWindow = window
+	warnTimes = newKeyCache(maxEscalationKeys)
+
+	if threshold > 0 {
+		atomic.StoreInt32(&escalationEnabled, 1)
+	} else {
+		atomic.StoreInt32(&escalationEnabled, 0)
+	}
+	escalationMutex.Unlock()
+}
+
+// escalateWarning records a written WARN message and logs it at ERROR once it
+// crosses the escalation threshold.
+func escalateWarning(m string) {
+	if atomic.LoadInt32(&escalationEnabled) == 0 {
+		return
+	}
+
+	escalationMutex.Lock()
+	threshold, window := escalationThreshold, escalationWindow
+
+	// Escalation may have been turned off since the check above.
+	if threshold <= 0 {
+		escalationMutex.Unlock()
+		return
+	}
+
+	t := now()
+
+	var times []time.Time
+	if v, ok := warnTimes.Get(m); ok {
+		times = v.([]time.Time)
+	}
+	times = append(recentWarnings(times, t, window), t)
+
+	escalate := len(times) >= threshold
+	if escalate {
+		warnTimes.Delete(m)
+	} else {
+		warnTimes.Set(m, times)
+	}
+
+	escalationMutex.Unlock()
+
+	if escalate && enabled(ERROR) {
+		output(ERROR, fmt.Sprintf("%s (logged %d times at WARN within %s)", m, threshold, window))
+	}
+}
+
+// recentWarnings returns the times that are within window of t.
+func recentWarnings(times []time.Time, t time.Time, window time.Duration) []time.Time {
+	for len(times) > 0 && t.Sub(times[0]) > window {
+		times = times[1:]
+	}
+
+	return times
+}
@@ -0,0 +1,86 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWarnEscalation(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	defer func() { now = time.Now }()
+	defer SetWarnEscalation(0, 0)
+
+	current := time.Date(2013, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time {
+		return current
+	}
+
+	SetWarnEscalation(3, time.Minute)
+
+	Warn("disk almost full")
+	Warn("other warning")
+	current = current.Add(30 * time.Second)
+	Warn("disk almost full")
+
+	// The first warning falls out of the window before the third arrives.
+	current = current.Add(45 * time.Second)
+	Warn("disk almost full")
+
+	if entries := w.Entries(); len(entries) != 4 {
+		t.Fatalf("expected no escalation yet, got %+v", entries)
+	}
+
+	Warnf("disk almost %s", "full")
+
+	entries := w.Entries()
+	if len(entries) != 6 {
+		t.Fatalf("expected 5 warnings and 1 escalation, got %+v", entries)
+	}
+
+	if entries[4].priority != "warning" || entries[4].message != "disk almost full" {
+		t.Errorf("expected original warning to pass through, got %+v", entries[4])
+	}
+
+	if entries[5].priority != "err" || entries[5].message != "disk almost full (logged 3 times at WARN within 1m0s)" {
+		t.Errorf("unexpected escalation: %+v", entries[5])
+	}
+
+	// The count restarts after escalating.
+	Warn("disk almost full")
+	if entries = w.Entries(); len(entries) != 7 || entries[6].priority != "warning" {
+		t.Errorf("expected count to restart after escalation, got %+v", entries)
+	}
+}
+
+func TestWarnEscalationDisabled(t *testing.T) {
+	w := setupTestWriter(TRACE)
+
+	for i := 0; i < 10; i++ {
+		Warn("repeated")
+	}
+
+	for _, e := range w.Entries() {
+		if e.priority != "warning" {
+			t.Errorf("unexpected escalation while disabled: %+v", e)
+		}
+	}
+}
+
+func TestWarnEscalationBoundsTrackedMessages(t *testing.T) {
+	setupTestWriter(TRACE)
+	defer SetWarnEscalation(0, 0)
+
+	SetWarnEscalation(2, time.Hour)
+
+	for i := 0; i < maxEscalationKeys+10; i++ {
+		Warnf("warning %d", i)
+	}
+
+	escalationMutex.Lock()
+	n := warnTimes.Len()
+	escalationMutex.Unlock()
+
+	if n != maxEscalationKeys {
+		t.Errorf("expected %d tracked messages, got %d", maxEscalationKeys, n)
+	}
+}
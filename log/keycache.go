@@ -0,0 +1,67 @@
+package log
+
+import (
+	"container/list"
+)
+
+// keyCache maps string keys to values, evicting the least recently used key
+// once it would hold more than max entries. It is not safe for concurrent
+// use.
+type keyCache struct {
+	max   int
+	items map[string]*list.Element
+	order *list.List
+}
+
+type keyCacheEntry struct {
+	key   string
+	value interface{}
+}
+
+func newKeyCache(max int) *keyCache {
+	return &keyCache{
+		max:   max,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// Get returns the value for key and marks key as most recently used.
+func (c *keyCache) Get(key string) (interface{}, bool) {
+	e, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToBack(e)
+	return e.Value.(*keyCacheEntry).value, true
+}
+
+// Set stores value for key and marks key as most recently used, evicting the
+// least recently used key if the cache is full.
+func (c *keyCache) Set(key string, value interface{}) {
+	if e, ok := c.items[key]; ok {
+		e.Value.(*keyCacheEntry).value = value
+		c.order.MoveToBack(e)
+		return
+	}
+
+	if c.order.Len() >= c.max {
+		oldest := c.order.Front()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*keyCacheEntry).key)
+	}
+
+	c.items[key] = c.order.PushBack(&keyCacheEntry{key, value})
+}
+
+func (c *keyCache) Delete(key string) {
+	if e, ok := c.items[key]; ok {
+		c.order.Remove(e)
+		delete(c.items, key)
+	}
+}
+
+func (c *keyCache) Len() int {
+	return c.order.Len()
+}
@@ -0,0 +1,42 @@
+package log
+
+import (
+	"testing"
+)
+
+func TestKeyCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newKeyCache(2)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 entries, got %d", c.Len())
+	}
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected least recently used key to be evicted")
+	}
+
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("expected a=1 to be kept, got %v %v", v, ok)
+	}
+
+	c.Set("a", 4)
+	c.Set("d", 5)
+
+	if v, _ := c.Get("a"); v != 4 {
+		t.Errorf("expected updated a=4 to be kept, got %v", v)
+	}
+
+	if _, ok := c.Get("c"); ok {
+		t.Error("expected c to be evicted after a was updated")
+	}
+
+	c.Delete("a")
+	if _, ok := c.Get("a"); ok || c.Len() != 1 {
+		t.Errorf("expected a to be deleted, %d entries remain", c.Len())
+	}
+}
@@ -0,0 +1,75 @@
+package log
+
+import (
+	"fmt"
+)
+
+var levelSet LevelSet
+var useLevelSet bool
+
+// LevelSet is a bitmask of LogLevels. It allows enabling an arbitrary subset
+// of levels (e.g. INFO and ERROR but not WARN) rather than everything at or
+// above a minimum level.
+type LevelSet uint8
+
+// NewLevelSet returns a LevelSet with the given levels enabled.
+func NewLevelSet(levels ...LogLevel) (s LevelSet) {
+	for _, l := range levels {
+		s.Enable(l)
+	}
+
+	return
+}
+
+func (s *LevelSet) Enable(level LogLevel) {
+	if validLevel(level) {
+		*s |= 1 << uint(level)
+	}
+}
+
+func (s *LevelSet) Disable(level LogLevel) {
+	if validLevel(level) {
+		*s &^= 1 << uint(level)
+	}
+}
+
+func (s LevelSet) Has(level LogLevel) bool {
+	return validLevel(level) && s&(1<<uint(level)) != 0
+}
+
+// SetLevelSet restricts logging to exactly the levels in s. It takes the
+// place of the minimum level until SetLogLevel is called again.
+func SetLevelSet(s LevelSet) {
+	mutex.Lock()
+	levelSet = s
+	useLevelSet = true
+	mutex.Unlock()
+}
+
+// GetLevelSet returns the LevelSet given to SetLevelSet and whether it is
+// currently active. It is inactive until SetLevelSet is called and again
+// after each call to SetLogLevel.
+func GetLevelSet() (s LevelSet, active bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	return levelSet, useLevelSet
+}
+
+func validLevel(level LogLevel) bool {
+	return level >= TRACE && level <= PANIC
+}
+
+// ValidateLevelRange returns an error unless min and max are known levels
+// and min is no more severe than max.
+func ValidateLevelRange(min, max LogLevel) (err error) {
+	switch {
+	case !validLevel(min):
+		err = fmt.Errorf("Invalid minimum log level %s", min)
+	case !validLevel(max):
+		err = fmt.Errorf("Invalid maximum log level %s", max)
+	case min > max:
+		err = fmt.Errorf("Minimum log level %s is more severe than maximum %s", min, max)
+	}
+
+	return
+}
@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"log/syslog"
 	"strconv"
+	"sync"
 )
 
 type LogLevel int8
@@ -17,7 +18,25 @@ var syslogHost string
 var syslogPort int = 0
 
 var minLevel LogLevel
-var logger *syslog.Writer
+var logger syslogWriter
+
+// mutex guards logger, the syslog address and the level settings so they
+// can be changed while other goroutines are logging.
+var mutex sync.RWMutex
+
+// syslogWriter is the subset of *syslog.Writer used by this package.
+type syslogWriter interface {
+	Emerg(m string) error
+	Crit(m string) error
+	Err(m string) error
+	Warning(m string) error
+	Info(m string) error
+	Debug(m string) error
+	Close() error
+}
+
+// newSyslogWriter connects to syslog for Initialize; tests replace it.
+var newSyslogWriter = dialSyslog
 
 const (
 	TRACE LogLevel = iota
@@ -30,110 +49,120 @@ const (
 )
 
 // Call Initialize after setting (or not setting) SyslogHost and SyslogPort when
-// they're read from configuration source.
+// they're read from configuration source. Calling it again replaces and
+// closes the current connection.
 func Initialize() {
 
-	var e error
+	mutex.RLock()
+	host, port := syslogHost, syslogPort
+	mutex.RUnlock()
 
-	if len(syslogHost) > 0 {
+	w, e := newSyslogWriter(host, port)
+	if e != nil {
+		panic(e)
+	}
 
-		logger, e = syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "skynet")
-		if e != nil {
-			panic(e)
-		}
-	} else {
-		logger, e = syslog.Dial("tcp4", syslogHost+":"+strconv.Itoa(syslogPort), syslog.LOG_INFO|syslog.LOG_USER, "skynet")
-		if e != nil {
-			panic(e)
-		}
+	setLogger(w)
+}
+
+// dialSyslog connects to the syslog daemon at host:port, or to the local
+// syslog daemon if no host is set.
+func dialSyslog(host string, port int) (syslogWriter, error) {
+	if len(host) > 0 {
+		return syslog.Dial("tcp4", host+":"+strconv.Itoa(port), syslog.LOG_INFO|syslog.LOG_USER, "skynet")
 	}
 
+	return syslog.New(syslog.LOG_INFO|syslog.LOG_USER, "skynet")
 }
 
 func Panic(messages ...interface{}) {
-	logger.Emerg(fromMulti(messages))
+	if enabled(PANIC) {
+		write(PANIC, fromMulti(messages...))
+	}
 }
 
 func Panicf(format string, messages ...interface{}) {
-	m := fmt.Sprintf(format, messages...)
-	logger.Emerg(m)
+	if enabled(PANIC) {
+		m := fmt.Sprintf(format, messages...)
+		write(PANIC, m)
+	}
 }
 
 func Fatal(messages ...interface{}) {
-	if minLevel <= FATAL {
-		logger.Crit(fromMulti(messages))
+	if enabled(FATAL) {
+		write(FATAL, fromMulti(messages...))
 	}
 }
 
 func Fatalf(format string, messages ...interface{}) {
-	if minLevel <= FATAL {
+	if enabled(FATAL) {
 		m := fmt.Sprintf(format, messages...)
-		logger.Crit(m)
+		write(FATAL, m)
 	}
 }
 
 func Error(messages ...interface{}) {
-	if minLevel <= ERROR {
-		logger.Err(fromMulti(messages))
+	if enabled(ERROR) {
+		write(ERROR, fromMulti(messages...))
 	}
 }
 
 func Errorf(format string, messages ...interface{}) {
-	if minLevel <= ERROR {
+	if enabled(ERROR) {
 		m := fmt.Sprintf(format, messages...)
-		logger.Err(m)
+		write(ERROR, m)
 	}
 }
 
 func Warn(messages ...interface{}) {
-	if minLevel <= WARN {
-		logger.Warning(fromMulti(messages))
+	if enabled(WARN) {
+		write(WARN, fromMulti(messages...))
 	}
 }
 
 func Warnf(format string, messages ...interface{}) {
-	if minLevel <= WARN {
+	if enabled(WARN) {
 		m := fmt.Sprintf(format, messages...)
-		logger.Warning(m)
+		write(WARN, m)
 	}
 }
 
 func Info(messages ...interface{}) {
-	if minLevel <= INFO {
-		logger.Info(fromMulti(messages))
+	if enabled(INFO) {
+		write(INFO, fromMulti(messages...))
 	}
 }
 
 func Infof(format string, messages ...interface{}) {
-	if minLevel <= INFO {
+	if enabled(INFO) {
 		m := fmt.Sprintf(format, messages...)
-		logger.Info(m)
+		write(INFO, m)
 	}
 }
 
 func Debug(messages ...interface{}) {
-	if minLevel <= DEBUG {
-		logger.Debug(fromMulti(messages))
+	if enabled(DEBUG) {
+		write(DEBUG, fromMulti(messages...))
 	}
 }
 
 func Debugf(format string, messages ...interface{}) {
-	if minLevel <= DEBUG {
+	if enabled(DEBUG) {
 		m := fmt.Sprintf(format, messages...)
-		logger.Debug(m)
+		write(DEBUG, m)
 	}
 }
 
 func Trace(messages ...interface{}) {
-	if minLevel <= TRACE {
-		logger.Debug(fromMulti(messages))
+	if enabled(TRACE) {
+		write(TRACE, fromMulti(messages...))
 	}
 }
 
 func Tracef(format string, messages ...interface{}) {
-	if minLevel <= TRACE {
+	if enabled(TRACE) {
 		m := fmt.Sprintf(format, messages...)
-		logger.Debug(m)
+		write(TRACE, m)
 	}
 }
 
@@ -163,45 +192,133 @@ func Printf(level LogLevel, format string, messages ...interface{}) {
 
 	switch level {
 	case DEBUG:
-		Debugf(format, messages)
+		Debugf(format, messages...)
 	case TRACE:
-		Tracef(format, messages)
+		Tracef(format, messages...)
 	case INFO:
-		Infof(format, messages)
+		Infof(format, messages...)
 	case WARN:
-		Warnf(format, messages)
+		Warnf(format, messages...)
 	case ERROR:
-		Errorf(format, messages)
+		Errorf(format, messages...)
 	case FATAL:
-		Fatalf(format, messages)
+		Fatalf(format, messages...)
 	case PANIC:
-		Panicf(format, messages)
+		Panicf(format, messages...)
 	}
 
 	return
 }
 
+// Forcef logs at level like Printf, but the message is never dropped by
+// SetFirstNThenSample. It still respects the configured log level.
+func Forcef(level LogLevel, format string, messages ...interface{}) {
+	if validLevel(level) && enabled(level) {
+		output(level, fmt.Sprintf(format, messages...))
+	}
+}
+
 func SetSyslogHost(host string) {
+	mutex.Lock()
 	syslogHost = host
+	mutex.Unlock()
 }
 
 func SetSyslogPort(port int) {
+	mutex.Lock()
 	syslogPort = port
+	mutex.Unlock()
 }
 
 func SetLogLevel(level LogLevel) {
+	mutex.Lock()
 	minLevel = level
+	useLevelSet = false
+	mutex.Unlock()
 }
 
+// GetLogLevel returns the minimum level set by SetLogLevel. While a LevelSet
+// is active it is not what's being applied; use GetLevelSet to check.
 func GetLogLevel() LogLevel {
+	mutex.RLock()
+	defer mutex.RUnlock()
 	return minLevel
 }
 
-func fromMulti(messages ...interface{}) string{
+// setLogger replaces the current writer and closes the previous one. The
+// close happens under the write lock, so no send can still be using it.
+func setLogger(w syslogWriter) {
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if logger != nil {
+		logger.Close()
+	}
+
+	logger = w
+}
+
+// enabled reports whether messages at level should be written, using the
+// LevelSet from SetLevelSet if one is active and the minimum level otherwise.
+func enabled(level LogLevel) bool {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	if useLevelSet {
+		return levelSet.Has(level)
+	}
+
+	return minLevel <= level
+}
+
+// write outputs m at level unless it is sampled out. Callers are responsible
+// for checking enabled(level).
+func write(level LogLevel, m string) {
+	if sample(level, m) {
+		output(level, m)
+	}
+}
+
+// output sends m to syslog at the priority matching level, counting and
+// analyzing it, and escalating repeated warnings.
+func output(level LogLevel, m string) {
+	countLevel(level)
+	analyzeMessage(m)
+	send(level, m)
+
+	if level == WARN {
+		escalateWarning(m)
+	}
+}
+
+// send writes m to the current writer. The read lock is held for the whole
+// call so setLogger can't close the writer part way through; a closed
+// *syslog.Writer would otherwise reconnect and leak the connection.
+func send(level LogLevel, m string) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+
+	switch level {
+	case TRACE, DEBUG:
+		logger.Debug(m)
+	case INFO:
+		logger.Info(m)
+	case WARN:
+		logger.Warning(m)
+	case ERROR:
+		logger.Err(m)
+	case FATAL:
+		logger.Crit(m)
+	case PANIC:
+		logger.Emerg(m)
+	}
+}
+
+func fromMulti(messages ...interface{}) string {
 	var r string
-	for x :=0; x < len(messages); x++  {
-		r = r + messages[x].(string)
-		if x < len(messages) {
+	for x := 0; x < len(messages); x++ {
+		r = r + fmt.Sprint(messages[x])
+		if x < len(messages)-1 {
 			r = r + "  "
 		}
 	}
@@ -228,3 +345,30 @@ func LevelFromString(l string) (level LogLevel) {
 
 	return
 }
+
+func (l LogLevel) String() string {
+	switch l {
+	case TRACE:
+		return "TRACE"
+	case DEBUG:
+		return "DEBUG"
+	case INFO:
+		return "INFO"
+	case WARN:
+		return "WARN"
+	case ERROR:
+		return "ERROR"
+	case FATAL:
+		return "FATAL"
+	case PANIC:
+		return "PANIC"
+	}
+
+	return "LogLevel(" + strconv.Itoa(int(l)) + ")"
+}
+
+// AllLevels returns every LogLevel from least to most severe. The slice is a
+// fresh copy on each call, so callers may modify it freely.
+func AllLevels() []LogLevel {
+	return []LogLevel{TRACE, DEBUG, INFO, WARN, ERROR, FATAL, PANIC}
+}
@@ -0,0 +1,435 @@
+package log
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type logEntry struct {
+	priority string
+	message  string
+}
+
+// testWriter records everything written to it in place of syslog. Writes
+// after Close are refused and counted, since a real *syslog.Writer would
+// reconnect.
+type testWriter struct {
+	mutex      sync.Mutex
+	entries    []logEntry
+	closed     bool
+	afterClose int
+}
+
+func (w *testWriter) write(priority, m string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		w.afterClose++
+		return errors.New("write after close")
+	}
+
+	w.entries = append(w.entries, logEntry{priority, m})
+	return nil
+}
+
+func (w *testWriter) Emerg(m string) error   { return w.write("emerg", m) }
+func (w *testWriter) Crit(m string) error    { return w.write("crit", m) }
+func (w *testWriter) Err(m string) error     { return w.write("err", m) }
+func (w *testWriter) Warning(m string) error { return w.write("warning", m) }
+func (w *testWriter) Info(m string) error    { return w.write("info", m) }
+func (w *testWriter) Debug(m string) error   { return w.write("debug", m) }
+
+func (w *testWriter) Close() error {
+	w.mutex.Lock()
+	w.closed = true
+	w.mutex.Unlock()
+	return nil
+}
+
+func (w *testWriter) Closed() bool {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.closed
+}
+
+func (w *testWriter) WritesAfterClose() int {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.afterClose
+}
+
+func (w *testWriter) Entries() []logEntry {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return append([]logEntry(nil), w.entries...)
+}
+
+func setupTestWriter(level LogLevel) *testWriter {
+	w := &testWriter{}
+	setLogger(w)
+	SetLogLevel(level)
+	return w
+}
+
+func TestPrintfFormatsMessages(t *testing.T) {
+	w := setupTestWriter(TRACE)
+
+	Printf(WARN, "%s has %d items", "cart", 3)
+
+	entries := w.Entries()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	if entries[0].priority != "warning" || entries[0].message != "cart has 3 items" {
+		t.Errorf("unexpected entry: %+v", entries[0])
+	}
+}
+
+func TestLogOnce(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	ResetOnce()
+	defer ResetOnce()
+
+	for i := 0; i < 3; i++ {
+		LogOnce(WARN, "old-api", "%s is deprecated", "OldAPI")
+		LogOnce(INFO, "other", "other message %d", i)
+	}
+
+	entries := w.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+
+	if entries[0].priority != "warning" || entries[0].message != "OldAPI is deprecated" {
+		t.Errorf("unexpected first entry: %+v", entries[0])
+	}
+
+	if entries[1].priority != "info" || entries[1].message != "other message 0" {
+		t.Errorf("unexpected second entry: %+v", entries[1])
+	}
+
+	ResetOnce()
+	LogOnce(WARN, "old-api", "%s is deprecated", "OldAPI")
+
+	if len(w.Entries()) != 3 {
+		t.Error("LogOnce didn't log again after ResetOnce")
+	}
+}
+
+func TestFromMulti(t *testing.T) {
+	if m := fromMulti("a", 1, "b"); m != "a  1  b" {
+		t.Errorf("unexpected message %q", m)
+	}
+}
+
+func TestLevelSet(t *testing.T) {
+	s := NewLevelSet(INFO, ERROR, WARN)
+	s.Disable(WARN)
+	s.Enable(TRACE)
+
+	for _, l := range AllLevels() {
+		expected := l == TRACE || l == INFO || l == ERROR
+		if s.Has(l) != expected {
+			t.Errorf("Has(%d) returned %v, expected %v", l, s.Has(l), expected)
+		}
+	}
+
+	s.Enable(LogLevel(42))
+	if s.Has(LogLevel(42)) {
+		t.Error("out of range level shouldn't be enabled")
+	}
+}
+
+func TestSetLevelSetFiltersLevels(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	defer SetLogLevel(TRACE)
+
+	SetLevelSet(NewLevelSet(INFO, ERROR))
+
+	Debug("debug")
+	Info("info")
+	Warn("warn")
+	Error("error")
+	Fatal("fatal")
+	Panic("panic")
+	Panicf("%s", "panicf")
+	Println(PANIC, "println")
+
+	entries := w.Entries()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d: %+v", len(entries), entries)
+	}
+
+	if entries[0].message != "info" || entries[1].message != "error" {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	if s, active := GetLevelSet(); !active || s != NewLevelSet(INFO, ERROR) {
+		t.Errorf("GetLevelSet returned %b, %v", s, active)
+	}
+
+	SetLevelSet(NewLevelSet(PANIC))
+	Error("error")
+	Panic("panic")
+
+	entries = w.Entries()
+	if len(entries) != 3 || entries[2].priority != "emerg" {
+		t.Errorf("enabled PANIC didn't pass through level set: %+v", entries)
+	}
+
+	SetLogLevel(WARN)
+	Info("info")
+	Warn("warn")
+
+	entries = w.Entries()
+	if len(entries) != 4 || entries[3].message != "warn" {
+		t.Errorf("SetLogLevel didn't replace level set: %+v", entries)
+	}
+
+	if _, active := GetLevelSet(); active {
+		t.Error("level set still active after SetLogLevel")
+	}
+}
+
+// stubSyslog makes Initialize create testWriters, returning every writer
+// created so far and a func restoring the real dialer.
+func stubSyslog() (writers func() []*testWriter, restore func()) {
+	var m sync.Mutex
+	var created []*testWriter
+
+	newSyslogWriter = func(host string, port int) (syslogWriter, error) {
+		w := &testWriter{}
+		w.write("connect", host+":"+strconv.Itoa(port))
+
+		m.Lock()
+		created = append(created, w)
+		m.Unlock()
+		return w, nil
+	}
+
+	writers = func() []*testWriter {
+		m.Lock()
+		defer m.Unlock()
+		return append([]*testWriter(nil), created...)
+	}
+
+	return writers, func() { newSyslogWriter = dialSyslog }
+}
+
+func TestInitializeClosesPreviousWriter(t *testing.T) {
+	writers, restore := stubSyslog()
+	defer restore()
+	defer SetSyslogHost("")
+	defer SetSyslogPort(0)
+
+	SetSyslogHost("logs.example.com")
+	SetSyslogPort(514)
+	Initialize()
+	SetSyslogPort(1514)
+	Initialize()
+
+	w := writers()
+	if len(w) != 2 {
+		t.Fatalf("expected 2 writers, got %d", len(w))
+	}
+
+	if !w[0].Closed() || w[1].Closed() {
+		t.Errorf("expected only the first writer to be closed, got %v %v", w[0].Closed(), w[1].Closed())
+	}
+
+	if e := w[1].Entries(); e[0].message != "logs.example.com:1514" {
+		t.Errorf("Initialize didn't use the current address: %+v", e)
+	}
+}
+
+func TestConcurrentInitialize(t *testing.T) {
+	writers, restore := stubSyslog()
+	defer restore()
+	defer SetSyslogPort(0)
+	defer SetLogLevel(TRACE)
+
+	Initialize()
+
+	var wg sync.WaitGroup
+	done := make(chan bool)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					Info("info")
+					Errorf("error %d", 1)
+					Println(WARN, "warn")
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 1000; i++ {
+		SetSyslogPort(i)
+		Initialize()
+		if i%2 == 0 {
+			SetLogLevel(LogLevel(i % 6))
+		} else {
+			SetLevelSet(NewLevelSet(INFO, ERROR))
+		}
+	}
+
+	close(done)
+	wg.Wait()
+
+	w := writers()
+	for i, tw := range w {
+		if i < len(w)-1 && !tw.Closed() {
+			t.Errorf("writer %d wasn't closed after being replaced", i)
+		}
+
+		if n := tw.WritesAfterClose(); n != 0 {
+			t.Errorf("writer %d was written to %d times after being closed", i, n)
+		}
+	}
+}
+
+// blockingWriter holds up Info calls until release is closed.
+type blockingWriter struct {
+	testWriter
+	started chan bool
+	release chan bool
+}
+
+func (w *blockingWriter) Info(m string) error {
+	w.started <- true
+	<-w.release
+	return w.testWriter.Info(m)
+}
+
+func TestInitializeWaitsForInFlightWrites(t *testing.T) {
+	_, restore := stubSyslog()
+	defer restore()
+	defer SetLogLevel(TRACE)
+
+	w := &blockingWriter{
+		started: make(chan bool),
+		release: make(chan bool),
+	}
+	setLogger(w)
+	SetLogLevel(TRACE)
+
+	go Info("in flight")
+	<-w.started
+
+	initialized := make(chan bool)
+	go func() {
+		Initialize()
+		initialized <- true
+	}()
+
+	// Give Initialize time to close the writer if it isn't waiting.
+	time.Sleep(50 * time.Millisecond)
+
+	if w.Closed() {
+		t.Error("writer was closed while a write was in flight")
+	}
+
+	close(w.release)
+	<-initialized
+
+	if !w.Closed() {
+		t.Error("writer wasn't closed after being replaced")
+	}
+
+	if n := w.WritesAfterClose(); n != 0 {
+		t.Errorf("writer was written to %d times after being closed", n)
+	}
+}
+
+func TestDialSyslogRemoteHost(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			received <- err.Error()
+			return
+		}
+		defer c.Close()
+
+		line, _ := bufio.NewReader(c).ReadString('\n')
+		received <- line
+	}()
+
+	port := l.Addr().(*net.TCPAddr).Port
+	w, err := dialSyslog("127.0.0.1", port)
+	if err != nil {
+		t.Fatalf("failed to dial configured host: %v", err)
+	}
+	defer w.Close()
+
+	w.Info("remote message")
+
+	if line := <-received; !strings.Contains(line, "remote message") {
+		t.Errorf("expected message to reach the configured host, got %q", line)
+	}
+}
+
+func TestLevelStringRoundTrip(t *testing.T) {
+	for _, l := range AllLevels() {
+		if LevelFromString(l.String()) != l {
+			t.Errorf("%s didn't round trip through LevelFromString", l)
+		}
+	}
+
+	if s := LogLevel(42).String(); s != "LogLevel(42)" {
+		t.Errorf("unexpected string for unknown level: %s", s)
+	}
+}
+
+func TestAllLevelsReturnsCopy(t *testing.T) {
+	levels := AllLevels()
+	levels[0], levels[1] = PANIC, PANIC
+
+	levels = AllLevels()
+	if len(levels) != 7 || levels[0] != TRACE || levels[1] != DEBUG {
+		t.Errorf("AllLevels was modified by a caller: %v", levels)
+	}
+
+	for i := 1; i < len(levels); i++ {
+		if levels[i-1] >= levels[i] {
+			t.Errorf("levels aren't ordered by severity: %v", levels)
+		}
+	}
+}
+
+func TestValidateLevelRange(t *testing.T) {
+	valid := [][2]LogLevel{{TRACE, PANIC}, {INFO, ERROR}, {WARN, WARN}}
+	for _, r := range valid {
+		if err := ValidateLevelRange(r[0], r[1]); err != nil {
+			t.Errorf("expected %s-%s to be valid, got %v", r[0], r[1], err)
+		}
+	}
+
+	invalid := [][2]LogLevel{{ERROR, INFO}, {PANIC, TRACE}, {LogLevel(-1), INFO}, {INFO, LogLevel(42)}}
+	for _, r := range invalid {
+		if err := ValidateLevelRange(r[0], r[1]); err == nil {
+			t.Errorf("expected %s-%s to be invalid", r[0], r[1])
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package log
+
+import (
+	"sync"
+)
+
+var onceMutex sync.Mutex
+var onceKeys = make(map[string]bool)
+
+// LogOnce logs the formatted message at level only the first time key is
+// seen by this process; later calls with the same key are no-ops. Useful for
+// deprecation warnings that would otherwise repeat on every call.
+func LogOnce(level LogLevel, key, format string, messages ...interface{}) {
+	onceMutex.Lock()
+	seen := onceKeys[key]
+	onceKeys[key] = true
+	onceMutex.Unlock()
+
+	if !seen {
+		Printf(level, format, messages...)
+	}
+}
+
+// ResetOnce forgets every key recorded by LogOnce.
+func ResetOnce() {
+	onceMutex.Lock()
+	onceKeys = make(map[string]bool)
+	onceMutex.Unlock()
+}
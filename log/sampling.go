@@ -0,0 +1,74 @@
+package log
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// maxSampledKeys bounds how many keys have their messages counted; the least
+// recently logged key is forgotten once the limit is reached.
+const maxSampledKeys = 1024
+
+var samplingEnabled int32
+var samplingMutex sync.Mutex
+var samplingFirstN, samplingEveryAfter int
+var samplingKey func(level LogLevel, m string) string
+var samplingCounts = newKeyCache(maxSampledKeys)
+
+// SetFirstNThenSample writes the first firstN messages for each key in full
+// and after that only every everyAfter'th one. key derives the key from a
+// message; if nil, the level and message text are used. Sampled-out
+// messages are not counted or escalated, and FATAL and PANIC are never
+// sampled; use Forcef for other messages that must always be written. At
+// most 1024 keys are counted at once, so a key that has been evicted starts
+// over with its first firstN messages. An everyAfter below 1 turns sampling
+// off.
+func SetFirstNThenSample(firstN, everyAfter int, key func(level LogLevel, m string) string) {
+	samplingMutex.Lock()
+	samplingFirstN = firstN
+	samplingEveryAfter = everyAfter
+	samplingKey = key
+	samplingCounts = newKeyCache(maxSampledKeys)
+
+	if everyAfter >= 1 {
+		atomic.StoreInt32(&samplingEnabled, 1)
+	} else {
+		atomic.StoreInt32(&samplingEnabled, 0)
+	}
+	samplingMutex.Unlock()
+}
+
+// sample reports whether a message at level should be written.
+func sample(level LogLevel, m string) bool {
+	if level >= FATAL || atomic.LoadInt32(&samplingEnabled) == 0 {
+		return true
+	}
+
+	samplingMutex.Lock()
+	defer samplingMutex.Unlock()
+
+	// Sampling may have been turned off since the check above.
+	if samplingEveryAfter < 1 {
+		return true
+	}
+
+	var k string
+	if samplingKey != nil {
+		k = samplingKey(level, m)
+	} else {
+		k = level.String() + " " + m
+	}
+
+	var n uint64
+	if v, ok := samplingCounts.Get(k); ok {
+		n = v.(uint64)
+	}
+	n++
+	samplingCounts.Set(k, n)
+
+	if n <= uint64(samplingFirstN) {
+		return true
+	}
+
+	return (n-uint64(samplingFirstN))%uint64(samplingEveryAfter) == 0
+}
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestFirstNThenSample(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	defer SetFirstNThenSample(0, 0, nil)
+
+	SetFirstNThenSample(3, 4, nil)
+
+	for i := 1; i <= 15; i++ {
+		Infof("%s", "cache miss")
+	}
+	Warn("cache miss")
+
+	infos := 0
+	for _, e := range w.Entries() {
+		if e.priority == "info" {
+			infos++
+		}
+	}
+
+	// The first 3 pass, then every 4th of the remaining 12.
+	if infos != 6 {
+		t.Errorf("expected 6 INFO entries, got %d", infos)
+	}
+
+	// A different level is a different key with its own count.
+	entries := w.Entries()
+	if last := entries[len(entries)-1]; last.priority != "warning" {
+		t.Errorf("expected WARN with the same text to pass, got %+v", last)
+	}
+}
+
+func TestFirstNThenSampleKeyFunc(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	defer SetFirstNThenSample(0, 0, nil)
+
+	// Key on the message without its trailing request number.
+	SetFirstNThenSample(1, 2, func(level LogLevel, m string) string {
+		return strings.TrimRight(m, "0123456789")
+	})
+
+	var passed []string
+	for i := 1; i <= 5; i++ {
+		Info("request " + strconv.Itoa(i))
+	}
+
+	for _, e := range w.Entries() {
+		passed = append(passed, e.message)
+	}
+
+	expected := []string{"request 1", "request 3", "request 5"}
+	if strings.Join(passed, ",") != strings.Join(expected, ",") {
+		t.Errorf("expected %v to pass, got %v", expected, passed)
+	}
+}
+
+func TestFirstNThenSampleNeverDropsFatal(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	defer SetFirstNThenSample(0, 0, nil)
+
+	SetFirstNThenSample(0, 100, nil)
+
+	Info("info")
+	Fatal("fatal")
+	Fatal("fatal")
+	Panic("panic")
+
+	entries := w.Entries()
+	if len(entries) != 3 || entries[0].priority != "crit" || entries[2].priority != "emerg" {
+		t.Errorf("expected only FATAL and PANIC to pass, got %+v", entries)
+	}
+}
+
+func TestForcefBypassesSampling(t *testing.T) {
+	w := setupTestWriter(INFO)
+	defer SetFirstNThenSample(0, 0, nil)
+
+	SetFirstNThenSample(0, 100, nil)
+
+	Info("dropped")
+	Forcef(INFO, "forced %d", 1)
+	Forcef(INFO, "forced %d", 1)
+	Forcef(DEBUG, "below the log level")
+	Forcef(LogLevel(42), "unknown level")
+
+	entries := w.Entries()
+	if len(entries) != 2 || entries[0].message != "forced 1" || entries[1].message != "forced 1" {
+		t.Errorf("expected only forced INFO messages to pass, got %+v", entries)
+	}
+}
+
+func TestFirstNThenSampleBoundsKeys(t *testing.T) {
+	setupTestWriter(TRACE)
+	defer SetFirstNThenSample(0, 0, nil)
+
+	SetFirstNThenSample(1, 2, nil)
+
+	for i := 0; i < maxSampledKeys+10; i++ {
+		Infof("message %d", i)
+	}
+
+	samplingMutex.Lock()
+	n := samplingCounts.Len()
+	samplingMutex.Unlock()
+
+	if n != maxSampledKeys {
+		t.Errorf("expected %d counted keys, got %d", maxSampledKeys, n)
+	}
+}
@@ -0,0 +1,115 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// exit is called by the StdLogger Fatal methods; tests replace it.
+var exit = os.Exit
+
+var exitCodeMutex sync.RWMutex
+var exitCodes = make(map[LogLevel]int)
+
+// SetExitCode sets the code passed to os.Exit by the Fatal methods of
+// StdLoggers created with NewStdLogger(level). The key is the level the
+// logger was created with, not FATAL, which Fatal always logs at: so
+// SetExitCode(FATAL, 2) only affects NewStdLogger(FATAL). The code is looked
+// up each time Fatal is called, so changing a mapping also affects existing
+// loggers. Levels without a mapping exit with 1.
+func SetExitCode(level LogLevel, code int) {
+	exitCodeMutex.Lock()
+	exitCodes[level] = code
+	exitCodeMutex.Unlock()
+}
+
+func exitCode(level LogLevel) int {
+	exitCodeMutex.RLock()
+	defer exitCodeMutex.RUnlock()
+
+	if code, ok := exitCodes[level]; ok {
+		return code
+	}
+
+	return 1
+}
+
+// StdLogger exposes the Print, Fatal and Panic methods of the standard
+// library's *log.Logger on top of this package, so existing *log.Logger
+// usages can be migrated gradually.
+type StdLogger struct {
+	level LogLevel
+}
+
+// NewStdLogger returns a StdLogger whose Print methods log at level. An
+// unknown level falls back to INFO.
+func NewStdLogger(level LogLevel) *StdLogger {
+	if !validLevel(level) {
+		level = INFO
+	}
+
+	return &StdLogger{
+		level: level,
+	}
+}
+
+func (l *StdLogger) Print(v ...interface{}) {
+	l.output(l.level, fmt.Sprint(v...))
+}
+
+func (l *StdLogger) Printf(format string, v ...interface{}) {
+	l.output(l.level, fmt.Sprintf(format, v...))
+}
+
+func (l *StdLogger) Println(v ...interface{}) {
+	l.output(l.level, sprintln(v...))
+}
+
+// Fatal logs at FATAL and then calls os.Exit with the code set by
+// SetExitCode for the logger's level, or 1. Like the standard library, the
+// message is always written, whatever the package's level settings.
+func (l *StdLogger) Fatal(v ...interface{}) {
+	write(FATAL, fmt.Sprint(v...))
+	exit(exitCode(l.level))
+}
+
+func (l *StdLogger) Fatalf(format string, v ...interface{}) {
+	write(FATAL, fmt.Sprintf(format, v...))
+	exit(exitCode(l.level))
+}
+
+func (l *StdLogger) Fatalln(v ...interface{}) {
+	write(FATAL, sprintln(v...))
+	exit(exitCode(l.level))
+}
+
+// Panic logs at PANIC and then panics with the message. The message is
+// always written, whatever the package's level settings.
+func (l *StdLogger) Panic(v ...interface{}) {
+	m := fmt.Sprint(v...)
+	write(PANIC, m)
+	panic(m)
+}
+
+func (l *StdLogger) Panicf(format string, v ...interface{}) {
+	m := fmt.Sprintf(format, v...)
+	write(PANIC, m)
+	panic(m)
+}
+
+func (l *StdLogger) Panicln(v ...interface{}) {
+	m := sprintln(v...)
+	write(PANIC, m)
+	panic(m)
+}
+
+func (l *StdLogger) output(level LogLevel, m string) {
+	Printf(level, "%s", m)
+}
+
+// sprintln formats like fmt.Sprintln without the trailing newline.
+func sprintln(v ...interface{}) string {
+	m := fmt.Sprintln(v...)
+	return m[:len(m)-1]
+}
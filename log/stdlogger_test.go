@@ -0,0 +1,151 @@
+package log
+
+import (
+	"os"
+	"testing"
+)
+
+func TestStdLoggerPrint(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	l := NewStdLogger(WARN)
+
+	l.Print("a", 1)
+	l.Printf("%s-%d", "b", 2)
+	l.Println("c", 3)
+
+	entries := w.Entries()
+	expected := []logEntry{
+		{"warning", "a1"},
+		{"warning", "b-2"},
+		{"warning", "c 3"},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %d: %+v", len(expected), len(entries), entries)
+	}
+
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("expected %+v, got %+v", expected[i], entries[i])
+		}
+	}
+}
+
+func TestStdLoggerFatalExits(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	defer func() { exit = os.Exit }()
+
+	var codes []int
+	exit = func(code int) {
+		codes = append(codes, code)
+	}
+
+	l := NewStdLogger(INFO)
+	l.Fatal("fatal")
+	l.Fatalf("%s", "fatalf")
+	l.Fatalln("fatalln")
+
+	if len(codes) != 3 || codes[0] != 1 || codes[1] != 1 || codes[2] != 1 {
+		t.Errorf("expected exit(1) for every Fatal call, got %v", codes)
+	}
+
+	for _, e := range w.Entries() {
+		if e.priority != "crit" {
+			t.Errorf("expected Fatal to log at crit, got %+v", e)
+		}
+	}
+}
+
+func TestStdLoggerPanics(t *testing.T) {
+	w := setupTestWriter(TRACE)
+	l := NewStdLogger(INFO)
+
+	defer func() {
+		r := recover()
+		if r != "oops 1" {
+			t.Errorf("expected panic with message, got %v", r)
+		}
+
+		entries := w.Entries()
+		if len(entries) != 1 || entries[0].priority != "emerg" || entries[0].message != "oops 1" {
+			t.Errorf("unexpected entries: %+v", entries)
+		}
+	}()
+
+	l.Panicf("oops %d", 1)
+	t.Error("Panicf didn't panic")
+}
+
+func TestStdLoggerExitCodes(t *testing.T) {
+	setupTestWriter(TRACE)
+	defer func() {
+		exit = os.Exit
+		exitCodes = make(map[LogLevel]int)
+	}()
+
+	var code int
+	exit = func(c int) {
+		code = c
+	}
+
+	SetExitCode(WARN, 3)
+	SetExitCode(ERROR, 4)
+
+	NewStdLogger(WARN).Fatal("warn")
+	if code != 3 {
+		t.Errorf("expected exit code 3, got %d", code)
+	}
+
+	NewStdLogger(ERROR).Fatalf("%s", "error")
+	if code != 4 {
+		t.Errorf("expected exit code 4, got %d", code)
+	}
+
+	info := NewStdLogger(INFO)
+	info.Fatalln("info")
+	if code != 1 {
+		t.Errorf("expected default exit code 1, got %d", code)
+	}
+
+	SetExitCode(INFO, 5)
+	info.Fatal("info")
+	if code != 5 {
+		t.Errorf("expected mapping set after creation to apply, got %d", code)
+	}
+
+	SetExitCode(FATAL, 2)
+	NewStdLogger(WARN).Fatal("warn")
+	if code != 3 {
+		t.Errorf("expected FATAL mapping not to affect a WARN logger, got %d", code)
+	}
+}
+
+func TestStdLoggerFatalIgnoresLevel(t *testing.T) {
+	w := setupTestWriter(PANIC)
+	defer SetLogLevel(TRACE)
+	defer func() { exit = os.Exit }()
+
+	exited := false
+	exit = func(code int) {
+		exited = true
+	}
+
+	SetLevelSet(NewLevelSet(INFO))
+	NewStdLogger(INFO).Fatalf("%s", "fatal")
+
+	entries := w.Entries()
+	if !exited || len(entries) != 1 || entries[0].priority != "crit" || entries[0].message != "fatal" {
+		t.Errorf("expected Fatalf to log before exiting, got %+v", entries)
+	}
+}
+
+func TestNewStdLoggerInvalidLevel(t *testing.T) {
+	w := setupTestWriter(TRACE)
+
+	NewStdLogger(LogLevel(42)).Print("print")
+
+	entries := w.Entries()
+	if len(entries) != 1 || entries[0].priority != "info" {
+		t.Errorf("expected invalid level to fall back to INFO, got %+v", entries)
+	}
+}